@@ -0,0 +1,201 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// AuthMethod is a Vault authentication method. It logs in against
+// a running Vault server and returns the login secret - which
+// contains the resulting client token together with its TTL and
+// renewability.
+//
+// Implementations must be safe to call concurrently since Store
+// may invoke Authenticate again - from a background go routine -
+// once the previously issued token can no longer be renewed.
+type AuthMethod interface {
+	Authenticate(ctx context.Context, client *vaultapi.Client) (*vaultapi.Secret, error)
+}
+
+// AppRoleAuth holds the Vault AppRole authentication credentials.
+//
+// It logs in via the `auth/approle/login` endpoint. See:
+// https://www.vaultproject.io/docs/auth/approle
+type AppRoleAuth struct {
+	// MountPath is the path the AppRole authentication backend
+	// has been mounted at. If empty, it defaults to "auth/approle".
+	MountPath string
+
+	// ID is the AppRole ID.
+	ID string
+
+	// Secret is the AppRole secret ID.
+	Secret string
+}
+
+var _ AuthMethod = (*AppRoleAuth)(nil)
+
+// Authenticate logs in via the AppRole authentication backend using
+// a.ID and a.Secret.
+func (a *AppRoleAuth) Authenticate(ctx context.Context, client *vaultapi.Client) (*vaultapi.Secret, error) {
+	return client.Logical().WriteWithContext(ctx, a.mountPath()+"/login", map[string]interface{}{
+		"role_id":   a.ID,
+		"secret_id": a.Secret,
+	})
+}
+
+func (a *AppRoleAuth) mountPath() string {
+	if a.MountPath == "" {
+		return "auth/approle"
+	}
+	return a.MountPath
+}
+
+// KubernetesAuth authenticates against Vault using the Kubernetes
+// authentication backend. It reads the pod's projected service
+// account JWT and exchanges it for a Vault token bound to Role.
+//
+// See: https://www.vaultproject.io/docs/auth/kubernetes
+type KubernetesAuth struct {
+	// MountPath is the path the Kubernetes authentication backend
+	// has been mounted at. If empty, it defaults to "auth/kubernetes".
+	MountPath string
+
+	// Role is the Vault role to authenticate as. It must be bound,
+	// on the Vault server, to the service account that KES runs as.
+	Role string
+
+	// JWTPath is the filesystem path of the projected service
+	// account token. If empty, it defaults to the well-known
+	// in-cluster path:
+	//   /var/run/secrets/kubernetes.io/serviceaccount/token
+	JWTPath string
+}
+
+var _ AuthMethod = (*KubernetesAuth)(nil)
+
+// Authenticate reads the service account JWT from k.JWTPath and
+// exchanges it for a Vault token bound to k.Role.
+func (k *KubernetesAuth) Authenticate(ctx context.Context, client *vaultapi.Client) (*vaultapi.Secret, error) {
+	jwt, err := ioutil.ReadFile(k.jwtPath())
+	if err != nil {
+		return nil, fmt.Errorf("vault: failed to read service account token: %v", err)
+	}
+
+	return client.Logical().WriteWithContext(ctx, k.mountPath()+"/login", map[string]interface{}{
+		"role": k.Role,
+		"jwt":  string(jwt),
+	})
+}
+
+func (k *KubernetesAuth) mountPath() string {
+	if k.MountPath == "" {
+		return "auth/kubernetes"
+	}
+	return k.MountPath
+}
+
+func (k *KubernetesAuth) jwtPath() string {
+	if k.JWTPath == "" {
+		return "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	}
+	return k.JWTPath
+}
+
+// TokenAuth authenticates with a pre-issued, static Vault token
+// instead of logging in via an auth backend. Since the token is
+// not obtained from a login request, Store cannot renew it and
+// treats it as non-renewable - it is the operator's responsibility
+// to ensure the token stays valid (e.g. a periodic or root token).
+type TokenAuth struct {
+	// Token is the Vault token to use. If empty, the token is
+	// read from the VAULT_TOKEN environment variable.
+	Token string
+}
+
+var _ AuthMethod = (*TokenAuth)(nil)
+
+// Authenticate wraps t.Token, or the VAULT_TOKEN environment
+// variable if t.Token is empty, in a non-renewable secret.
+func (t *TokenAuth) Authenticate(context.Context, *vaultapi.Client) (*vaultapi.Secret, error) {
+	token := t.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, errors.New("vault: no token specified")
+	}
+	return &vaultapi.Secret{
+		Auth: &vaultapi.SecretAuth{
+			ClientToken: token,
+		},
+	}, nil
+}
+
+// LDAPAuth authenticates against Vault's LDAP authentication
+// backend using a username and password.
+//
+// See: https://www.vaultproject.io/docs/auth/ldap
+type LDAPAuth struct {
+	// MountPath is the path the LDAP authentication backend has
+	// been mounted at. If empty, it defaults to "auth/ldap".
+	MountPath string
+
+	// Username is the LDAP username to authenticate with.
+	Username string
+
+	// Password is the LDAP password to authenticate with.
+	Password string
+}
+
+var _ AuthMethod = (*LDAPAuth)(nil)
+
+// Authenticate logs in via the LDAP authentication backend using
+// l.Username and l.Password.
+func (l *LDAPAuth) Authenticate(ctx context.Context, client *vaultapi.Client) (*vaultapi.Secret, error) {
+	return client.Logical().WriteWithContext(ctx, l.mountPath()+"/login/"+l.Username, map[string]interface{}{
+		"password": l.Password,
+	})
+}
+
+func (l *LDAPAuth) mountPath() string {
+	if l.MountPath == "" {
+		return "auth/ldap"
+	}
+	return l.MountPath
+}
+
+// tokenFromSecret extracts the client token, its TTL and whether
+// it is renewable from the secret returned by a Vault login request.
+func tokenFromSecret(secret *vaultapi.Secret, err error) (string, time.Duration, bool, error) {
+	if err != nil || secret == nil {
+		if err == nil {
+			err = errors.New("vault: login returned no secret")
+		}
+		return "", 0, false, err
+	}
+
+	token, err := secret.TokenID()
+	if err != nil {
+		return "", 0, false, err
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return "", 0, false, err
+	}
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return "", 0, false, err
+	}
+	return token, ttl, renewable, nil
+}
@@ -14,28 +14,23 @@ package vault
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/minio/kes"
 )
 
-// AppRole holds the Vault AppRole
-// authentication credentials and
-// a duration after which the
-// authentication should be retried
-// whenever it fails.
-type AppRole struct {
-	ID     string // The AppRole  ID
-	Secret string // The Approle secret ID
-	Retry  time.Duration
-}
-
 // Store is a key-value store that saves key-value
 // pairs as entries on Vault's K/V secret backend.
 type Store struct {
@@ -51,9 +46,36 @@ type Store struct {
 	// case you may set KeyStore.Location = "key/my-app".
 	Location string
 
-	// AppRole contains the Vault AppRole authentication
-	// credentials.
-	AppRole AppRole
+	// EnginePath is the path of the K/V secret engine that
+	// this KeyStore will use to save/fetch secret keys. If
+	// empty, it defaults to "kv".
+	EnginePath string
+
+	// EngineVersion is the version of the K/V secret engine
+	// mounted at EnginePath - either 1 or 2. If 0, the
+	// KeyStore will auto-detect the version by inspecting
+	// the mount at EnginePath once it authenticates.
+	EngineVersion int
+
+	// AuthMethod is the method used to authenticate to the Vault
+	// server - e.g. &AppRoleAuth{...}, &KubernetesAuth{...},
+	// &TokenAuth{...} or &LDAPAuth{...}.
+	AuthMethod AuthMethod
+
+	// AuthRetry is the duration after which a failed authentication
+	// attempt is retried. If 0, it defaults to 5 seconds.
+	AuthRetry time.Duration
+
+	// Timeout is the duration after which an operation (Get, Create,
+	// Delete, ...) is canceled if the caller-supplied context has no
+	// deadline of its own. If 0, it defaults to 15 seconds.
+	Timeout time.Duration
+
+	// RenewalNotify, if non-nil, receives a RenewalEvent whenever
+	// the Vault client token is renewed or renewal fails terminally.
+	// Sends are non-blocking - if the channel is not ready to
+	// receive, the event is dropped.
+	RenewalNotify chan<- RenewalEvent
 
 	// StatusPingAfter is the duration after which
 	// the KeyStore will check the status of the Vault
@@ -70,18 +92,37 @@ type Store struct {
 	ErrorLog *log.Logger
 
 	// Path to the mTLS client private key to authenticate to
-	// the Vault server.
+	// the Vault server. Mutually exclusive with ClientKeyPEM.
 	ClientKeyPath string
 
 	// Path to the mTLS client certificate to authenticate to
-	// the Vault server.
+	// the Vault server. Mutually exclusive with ClientCertPEM.
 	ClientCertPath string
 
 	// Path to the root CA certificate(s) used to verify the
 	// TLS certificate of the Vault server. If empty, the
-	// host's root CA set is used.
+	// host's root CA set is used. Mutually exclusive with CAPEM.
 	CAPath string
 
+	// ClientKeyPEM is the PEM-encoded mTLS client private key to
+	// authenticate to the Vault server. Mutually exclusive with
+	// ClientKeyPath.
+	//
+	// It allows passing key material that has been sourced from
+	// somewhere other than the local filesystem - e.g. a Kubernetes
+	// Secret or an HSM - without first writing it to a temp file.
+	ClientKeyPEM []byte
+
+	// ClientCertPEM is the PEM-encoded mTLS client certificate to
+	// authenticate to the Vault server. Mutually exclusive with
+	// ClientCertPath.
+	ClientCertPEM []byte
+
+	// CAPEM is a set of PEM-encoded root CA certificates used to
+	// verify the TLS certificate of the Vault server. If empty,
+	// the host's root CA set is used. Mutually exclusive with CAPath.
+	CAPEM []byte
+
 	// The Vault namespace used to separate and isolate different
 	// organizations / tenants at the same Vault instance. If
 	// non-empty, the Vault client will send the
@@ -90,35 +131,50 @@ type Store struct {
 	// https://www.vaultproject.io/docs/enterprise/namespaces/index.html
 	Namespace string
 
-	client *vaultapi.Client
-	sealed bool
+	client  *vaultapi.Client
+	sealed  bool
+	version int // The detected/configured K/V engine version - either 1 or 2.
 }
 
 // Authenticate tries to establish a connection to
-// a Vault server using the approle credentials.
+// a Vault server using s.AuthMethod.
 // It returns an error if no connection could be
 // established - for instance because of invalid
 // authentication credentials.
 func (s *Store) Authenticate(context context.Context) error {
-	tlsConfig := &vaultapi.TLSConfig{
-		ClientKey:  s.ClientKeyPath,
-		ClientCert: s.ClientCertPath,
+	if s.AuthMethod == nil {
+		return errors.New("vault: no authentication method specified")
 	}
-	if s.CAPath != "" {
-		stat, err := os.Stat(s.CAPath)
-		if err != nil {
-			return fmt.Errorf("Failed to open '%s': %v", s.CAPath, err)
-		}
-		if stat.IsDir() {
-			tlsConfig.CAPath = s.CAPath
-		} else {
-			tlsConfig.CACert = s.CAPath
-		}
+	if (s.ClientKeyPath != "") && len(s.ClientKeyPEM) > 0 {
+		return errors.New("vault: ClientKeyPath and ClientKeyPEM must not be specified at the same time")
+	}
+	if (s.ClientCertPath != "") && len(s.ClientCertPEM) > 0 {
+		return errors.New("vault: ClientCertPath and ClientCertPEM must not be specified at the same time")
+	}
+	if (s.CAPath != "") && len(s.CAPEM) > 0 {
+		return errors.New("vault: CAPath and CAPEM must not be specified at the same time")
 	}
 
 	config := vaultapi.DefaultConfig()
 	config.Address = s.Addr
-	config.ConfigureTLS(tlsConfig)
+
+	// DefaultConfig already set up an *http.Transport with the HTTP/2
+	// upgrade, handshake timeout and minimum TLS version we want to
+	// keep - so we apply our TLS material to its existing
+	// TLSClientConfig instead of installing a fresh *http.Transport
+	// that would silently drop all of that.
+	transport, ok := config.HttpClient.Transport.(*http.Transport)
+	if !ok {
+		transport = &http.Transport{TLSClientConfig: &tls.Config{}}
+		config.HttpClient.Transport = transport
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if err := s.applyTLSConfig(transport.TLSClientConfig); err != nil {
+		return err
+	}
+
 	client, err := vaultapi.NewClient(config)
 	if err != nil {
 		return err
@@ -133,32 +189,194 @@ func (s *Store) Authenticate(context context.Context) error {
 
 	s.client = client
 
-	status, err := s.client.Sys().Health()
+	status, err := s.client.Sys().HealthWithContext(context)
 	if err != nil {
 		return err
 	}
 	s.sealed = status.Sealed
 
-	var token string
-	var ttl time.Duration
+	var secret *vaultapi.Secret
 	if !status.Sealed {
-		token, ttl, err = s.authenticate(s.AppRole)
+		secret, err = s.AuthMethod.Authenticate(context, s.client)
+		if err != nil {
+			return err
+		}
+		token, _, _, err := tokenFromSecret(secret, nil)
 		if err != nil {
 			return err
 		}
 		s.client.SetToken(token)
+
+		if err = s.detectEngineVersion(context); err != nil {
+			return err
+		}
 	}
 
 	go s.checkStatus(context, s.StatusPingAfter)
-	go s.renewAuthToken(context, s.AppRole, ttl)
+	go s.renewAuthToken(context, secret)
 	return nil
 }
 
+// detectEngineVersion determines which version of the K/V secret
+// engine is mounted at s.EnginePath and stores it at s.version.
+//
+// If s.EngineVersion is 1 or 2 it is used as-is - no request to
+// Vault is made. Otherwise, the mount table is queried to find the
+// "options.version" of the K/V engine at s.EnginePath. Engines that
+// don't report a version (e.g. the K/V v1 engine) default to v1.
+func (s *Store) detectEngineVersion(ctx context.Context) error {
+	if s.EngineVersion == 1 || s.EngineVersion == 2 {
+		s.version = s.EngineVersion
+		return nil
+	}
+
+	mounts, err := s.client.Sys().ListMountsWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("vault: failed to detect K/V engine version: %v", err)
+	}
+
+	mount, ok := mounts[s.enginePath()+"/"]
+	if !ok {
+		return fmt.Errorf("vault: failed to detect K/V engine version: no K/V engine mounted at '%s'", s.enginePath())
+	}
+	if mount.Options != nil && mount.Options["version"] == "2" {
+		s.version = 2
+	} else {
+		s.version = 1
+	}
+	return nil
+}
+
+// enginePath returns the configured K/V engine path or the
+// default "kv" if none has been set.
+func (s *Store) enginePath() string {
+	if s.EnginePath == "" {
+		return "kv"
+	}
+	return s.EnginePath
+}
+
+// location returns the Vault path at which the given key's value
+// can be read or written.
+func (s *Store) location(key string) string {
+	if s.version == 2 {
+		return path.Join(s.enginePath(), "data", s.Location, key)
+	}
+	return path.Join(s.enginePath(), s.Location, key)
+}
+
+// metadataLocation returns the Vault path at which the given key's
+// metadata - and therefore all of its versions - can be deleted.
+// It is only meaningful for the K/V v2 engine.
+func (s *Store) metadataLocation(key string) string {
+	return path.Join(s.enginePath(), "metadata", s.Location, key)
+}
+
+// applyTLSConfig resolves s's TLS fields and applies them to tlsConfig
+// in place, so that callers can seed tlsConfig with defaults (e.g. the
+// *http.Transport.TLSClientConfig that vaultapi.DefaultConfig already
+// configured) before this fills in the client certificate and CA pool.
+//
+// The client certificate/key and the CA pool are each resolved
+// independently: a PEM field is preferred over its path-based
+// counterpart, but one slot using PEM material does not force the
+// other slot to - e.g. a file-based client certificate can be
+// combined with an in-memory CA pool, or vice versa.
+func (s *Store) applyTLSConfig(tlsConfig *tls.Config) error {
+	switch {
+	case len(s.ClientCertPEM) > 0 || len(s.ClientKeyPEM) > 0:
+		cert, err := tls.X509KeyPair(s.ClientCertPEM, s.ClientKeyPEM)
+		if err != nil {
+			return fmt.Errorf("vault: failed to parse client certificate/key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case s.ClientCertPath != "" || s.ClientKeyPath != "":
+		cert, err := tls.LoadX509KeyPair(s.ClientCertPath, s.ClientKeyPath)
+		if err != nil {
+			return fmt.Errorf("vault: failed to load client certificate/key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	switch {
+	case len(s.CAPEM) > 0:
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(s.CAPEM) {
+			return errors.New("vault: failed to parse CA certificate(s)")
+		}
+		tlsConfig.RootCAs = pool
+	case s.CAPath != "":
+		pool, err := caPoolFromPath(s.CAPath)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return nil
+}
+
+// caPoolFromPath reads CA certificate(s) from caPath into a
+// *x509.CertPool. caPath may be a single PEM file or a directory
+// containing one or more PEM files.
+func caPoolFromPath(caPath string) (*x509.CertPool, error) {
+	stat, err := os.Stat(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("vault: failed to open '%s': %v", caPath, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !stat.IsDir() {
+		pem, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("vault: failed to read '%s': %v", caPath, err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("vault: failed to parse CA certificate(s) at '%s'", caPath)
+		}
+		return pool, nil
+	}
+
+	entries, err := os.ReadDir(caPath)
+	if err != nil {
+		return nil, fmt.Errorf("vault: failed to read '%s': %v", caPath, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		file := filepath.Join(caPath, entry.Name())
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("vault: failed to read '%s': %v", file, err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("vault: failed to parse CA certificate(s) at '%s'", file)
+		}
+	}
+	return pool, nil
+}
+
 var errSealed = kes.NewError(http.StatusForbidden, "key store is sealed")
 
+// withTimeout returns a derived context that is canceled after
+// s.Timeout (or a default of 15 seconds) unless ctx already carries
+// its own deadline - e.g. because the caller passed a context with
+// an explicit timeout rather than context.Background().
+func (s *Store) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 15 * time.Second
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // Get returns the value associated with the given key.
 // If no entry for the key exists it returns kes.ErrKeyNotFound.
-func (s *Store) Get(key string) (string, error) {
+func (s *Store) Get(ctx context.Context, key string) (string, error) {
 	if s.client == nil {
 		s.log(errNoConnection)
 		return "", errNoConnection
@@ -167,8 +385,11 @@ func (s *Store) Get(key string) (string, error) {
 		return "", errSealed
 	}
 
-	location := fmt.Sprintf("/kv/%s/%s", s.Location, key)
-	entry, err := s.client.Logical().Read(location)
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	location := s.location(key)
+	entry, err := s.client.Logical().ReadWithContext(ctx, location)
 	if err != nil || entry == nil {
 		// Vault will not return an error if e.g. the key existed but has
 		// been deleted. However, it will return (nil, nil) in this case.
@@ -179,8 +400,24 @@ func (s *Store) Get(key string) (string, error) {
 		return "", err
 	}
 
+	data := entry.Data
+	if s.version == 2 {
+		// The K/V v2 engine wraps the actual secret in a nested
+		// "data" field. A nil value there means the entry has no
+		// more (non-deleted/non-destroyed) versions.
+		v, ok := entry.Data["data"]
+		if !ok || v == nil {
+			return "", kes.ErrKeyNotFound
+		}
+		data, ok = v.(map[string]interface{})
+		if !ok {
+			s.logf("vault: failed to read '%s': invalid K/V format", location)
+			return "", errors.New("vault: invalid K/V entry format")
+		}
+	}
+
 	// Verify that we got a well-formed response from Vault
-	v, ok := entry.Data[key]
+	v, ok := data[key]
 	if !ok || v == nil {
 		s.logf("vault: failed to read '%s': entry exists but no secret key is present", location)
 		return "", errors.New("vault: K/V entry does not contain any value")
@@ -196,7 +433,7 @@ func (s *Store) Get(key string) (string, error) {
 // Create creates the given key-value pair at Vault if and only
 // if the given key does not exist. If such an entry already exists
 // it returns kes.ErrKeyExists.
-func (s *Store) Create(key, value string) error {
+func (s *Store) Create(ctx context.Context, key, value string) error {
 	if s.client == nil {
 		s.log(errNoConnection)
 		return errNoConnection
@@ -205,10 +442,40 @@ func (s *Store) Create(key, value string) error {
 		return errSealed
 	}
 
-	// We try to check whether key exists on the K/V store.
-	// If so, we must not overwrite it.
-	location := fmt.Sprintf("/kv/%s/%s", s.Location, key)
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	location := s.location(key)
+
+	if s.version == 2 {
+		// The K/V v2 engine supports a Check-And-Set write: writing
+		// with "options.cas = 0" only succeeds if the key has never
+		// had any version written before. If it has, Vault rejects
+		// the write with a 400 instead of silently overwriting it -
+		// which closes the create-then-write race that the v1 path
+		// below cannot avoid.
+		_, err := s.client.Logical().WriteWithContext(ctx, location, map[string]interface{}{
+			"data": map[string]interface{}{
+				key: value,
+			},
+			"options": map[string]interface{}{
+				"cas": 0,
+			},
+		})
+		if err != nil {
+			if isCASConflict(err) {
+				return kes.ErrKeyExists
+			}
+			s.logf("vault: failed to create '%s': %v", location, err)
+			return err
+		}
+		return nil
+	}
 
+	// The K/V v1 engine has no Check-And-Set support. We try to check
+	// whether the key exists on the K/V store first. If so, we must
+	// not overwrite it.
+	//
 	// Vault will return nil for the secret as well as a nil-error
 	// if the specified entry does not exist.
 	// More specifically the Vault server + client behaves as following:
@@ -226,7 +493,7 @@ func (s *Store) Create(key, value string) error {
 	// But when the client returns an error it does not mean that
 	// the entry does not exist but that some other error (e.g.
 	// network error) occurred.
-	switch secret, err := s.client.Logical().Read(location); {
+	switch secret, err := s.client.Logical().ReadWithContext(ctx, location); {
 	case err == nil && secret != nil:
 		return kes.ErrKeyExists
 	case err != nil:
@@ -240,7 +507,7 @@ func (s *Store) Create(key, value string) error {
 	// Since there is now way we can detect that reliable we require
 	// that whoever has the permission to create keys does that in
 	// a non-racy way.
-	_, err := s.client.Logical().Write(location, map[string]interface{}{
+	_, err := s.client.Logical().WriteWithContext(ctx, location, map[string]interface{}{
 		key: value,
 	})
 	if err != nil {
@@ -250,9 +517,31 @@ func (s *Store) Create(key, value string) error {
 	return nil
 }
 
+// isCASConflict reports whether err is the error Vault's K/V v2 engine
+// returns when a Check-And-Set write's "cas" value does not match the
+// current version of the entry - i.e. the entry already exists.
+//
+// A 400 alone is not sufficient: Vault also responds with 400 for
+// unrelated write failures (malformed payload, size limits, ...), so
+// we additionally look for the CAS-specific error message in the
+// response body before concluding it's a conflict.
+func isCASConflict(err error) bool {
+	var respErr *vaultapi.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, e := range respErr.Errors {
+		e = strings.ToLower(e)
+		if strings.Contains(e, "check-and-set") || strings.Contains(e, "did not match the current version") {
+			return true
+		}
+	}
+	return false
+}
+
 // Delete removes a the value associated with the given key
 // from Vault, if it exists.
-func (s *Store) Delete(key string) error {
+func (s *Store) Delete(ctx context.Context, key string) error {
 	if s.client == nil {
 		s.log(errNoConnection)
 		return errNoConnection
@@ -261,40 +550,144 @@ func (s *Store) Delete(key string) error {
 		return errSealed
 	}
 
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	// Vault will not return an error if an entry does not
 	// exist. Instead, it responds with 204 No Content and
 	// no body. In this case the client also returns a nil-error
 	// Therefore, we can just try to delete it in any case.
-	location := fmt.Sprintf("/kv/%s/%s", s.Location, key)
-	_, err := s.client.Logical().Delete(location)
+	location := s.location(key)
+	if s.version == 2 {
+		// Deleting the data path of a K/V v2 entry only soft-deletes
+		// its latest version. We delete the metadata instead, which
+		// purges all versions - matching the v1 Delete semantics.
+		location = s.metadataLocation(key)
+	}
+	_, err := s.client.Logical().DeleteWithContext(ctx, location)
 	if err != nil {
 		s.logf("vault: failed to delete '%s': %v", location, err)
 	}
 	return err
 }
 
-func (s *Store) authenticate(login AppRole) (token string, ttl time.Duration, err error) {
-	secret, err := s.client.Logical().Write("auth/approle/login", map[string]interface{}{
-		"role_id":   login.ID,
-		"secret_id": login.Secret,
-	})
-	if err != nil || secret == nil {
-		if err == nil {
-			// TODO: return non-nil error
-		}
-		return token, ttl, err
+// listLocation returns the Vault path at which the keys stored
+// under prefix can be enumerated via a LIST request.
+func (s *Store) listLocation(prefix string) string {
+	if s.version == 2 {
+		return path.Join(s.enginePath(), "metadata", s.Location, prefix)
 	}
+	return path.Join(s.enginePath(), s.Location, prefix)
+}
 
-	token, err = secret.TokenID()
-	if err != nil {
-		return token, ttl, err
+// List returns up to limit keys stored under prefix, in lexical
+// order, starting after cursor. If there are more keys than fit
+// into limit, next is the cursor to pass to a subsequent List call
+// to continue the listing; otherwise next is empty.
+//
+// A limit <= 0 returns all keys under prefix in one call.
+//
+// Vault K/V LIST requests return one level of the hierarchy at a
+// time. Entries that are themselves prefixes of other keys - i.e.
+// "sub-directories" - are reported with a trailing "/" and are
+// filtered out rather than recursed into.
+func (s *Store) List(ctx context.Context, prefix string, limit int, cursor string) ([]string, string, error) {
+	if s.client == nil {
+		s.log(errNoConnection)
+		return nil, "", errNoConnection
 	}
+	if s.sealed {
+		return nil, "", errSealed
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
-	ttl, err = secret.TokenTTL()
+	location := s.listLocation(prefix)
+	secret, err := s.client.Logical().ListWithContext(ctx, location)
 	if err != nil {
-		return token, ttl, err
+		s.logf("vault: failed to list '%s': %v", location, err)
+		return nil, "", err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, "", nil
+	}
+
+	raw, ok := secret.Data["keys"]
+	if !ok {
+		return nil, "", nil
+	}
+	rawKeys, ok := raw.([]interface{})
+	if !ok {
+		s.logf("vault: failed to list '%s': invalid K/V list format", location)
+		return nil, "", errors.New("vault: invalid K/V list format")
+	}
+
+	keys := make([]string, 0, len(rawKeys))
+	for _, k := range rawKeys {
+		key, ok := k.(string)
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(key, "/") {
+			// Skip sub-directories - List does not recurse into them.
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	keys, next := paginate(keys, limit, cursor)
+	return keys, next, nil
+}
+
+// paginate sorts keys lexically and returns the page of up to limit
+// entries starting after cursor, together with the cursor to pass to
+// the next call - or an empty string if that page is the last one.
+//
+// A limit <= 0 returns all entries in one page.
+func paginate(keys []string, limit int, cursor string) ([]string, string) {
+	sort.Strings(keys)
+
+	if cursor != "" {
+		start := sort.SearchStrings(keys, cursor)
+		if start < len(keys) && keys[start] == cursor {
+			start++ // cursor itself was already returned by a previous call
+		}
+		keys = keys[start:]
+	}
+
+	var next string
+	if limit > 0 && len(keys) > limit {
+		next = keys[limit-1]
+		keys = keys[:limit]
+	}
+	return keys, next
+}
+
+// RenewalEvent describes the outcome of a Vault client token
+// renewal, as reported on Store.RenewalNotify.
+type RenewalEvent struct {
+	// Renewed is true if the token was successfully renewed, and
+	// false if the renewal process failed terminally - i.e. a
+	// re-authentication via the Store's AuthMethod was required.
+	Renewed bool
+
+	// Secret is the renewed login secret. It is only set if
+	// Renewed is true.
+	Secret *vaultapi.Secret
+
+	// Err is the error that caused the renewal to fail terminally.
+	// It is only set if Renewed is false.
+	Err error
+}
+
+// authRetry returns the configured AuthRetry or, if unset, a
+// default of 5 seconds.
+func (s *Store) authRetry() time.Duration {
+	if s.AuthRetry == 0 {
+		return 5 * time.Second
 	}
-	return token, ttl, err
+	return s.AuthRetry
 }
 
 func (s *Store) checkStatus(ctx context.Context, delay time.Duration) {
@@ -303,7 +696,7 @@ func (s *Store) checkStatus(ctx context.Context, delay time.Duration) {
 	}
 	var timer *time.Timer
 	for {
-		status, err := s.client.Sys().Health()
+		status, err := s.client.Sys().HealthWithContext(ctx)
 		if err == nil {
 			s.sealed = status.Sealed
 		}
@@ -322,10 +715,14 @@ func (s *Store) checkStatus(ctx context.Context, delay time.Duration) {
 	}
 }
 
-func (s *Store) renewAuthToken(ctx context.Context, login AppRole, ttl time.Duration) {
-	if login.Retry == 0 {
-		login.Retry = 5 * time.Second
-	}
+// renewAuthToken keeps the Vault client token alive using a
+// vaultapi.LifetimeWatcher, which takes care of scheduling renewals
+// and handling non-renewable / max-TTL-reached tokens correctly.
+// Whenever the watcher gives up - because the token expired or
+// cannot be renewed any further - it falls back to a full
+// re-authentication via s.AuthMethod and starts a new watcher for
+// the resulting secret.
+func (s *Store) renewAuthToken(ctx context.Context, secret *vaultapi.Secret) {
 	for {
 		// If Vault is sealed we have to wait
 		// until it is unsealed again.
@@ -340,56 +737,92 @@ func (s *Store) renewAuthToken(ctx context.Context, login AppRole, ttl time.Dura
 			case <-timer.C:
 			}
 		}
-		// If the TTL is 0 we cannot renew the token.
-		// Therefore, we try to re-authenticate and
-		// get a new token. We repeat that until we
-		// successfully authenticate and got a token.
-		if ttl == 0 {
-			var (
-				token string
-				err   error
-			)
-			token, ttl, err = s.authenticate(login)
-			if err != nil {
-				ttl = 0
-				timer := time.NewTimer(login.Retry)
-				select {
-				case <-ctx.Done():
-					timer.Stop()
-					return
-				case <-timer.C:
-				}
-				continue
-			}
-			s.client.SetToken(token) // SetToken is safe to call from different go routines
-		}
 
-		// Now the client has token with a non-zero TTL
-		// such tht we can renew it. We repeat that until
-		// the renewable process fails once. In this case
-		// we try to re-authenticate again.
-		timer := time.NewTimer(ttl / 2)
-		for {
+		if secret == nil || secret.Auth == nil || !secret.Auth.Renewable {
+			// There is nothing to renew - e.g. a static token handed
+			// out by TokenAuth, or we haven't authenticated yet
+			// because Vault was sealed. Wait and then try to
+			// (re-)authenticate via the configured AuthMethod.
+			timer := time.NewTimer(s.authRetry())
 			select {
 			case <-ctx.Done():
 				timer.Stop()
 				return
 			case <-timer.C:
 			}
-			secret, err := s.client.Auth().Token().RenewSelf(int(ttl.Seconds()))
-			if err != nil || secret == nil {
-				break
+
+			newSecret, err := s.AuthMethod.Authenticate(ctx, s.client)
+			if err != nil {
+				s.notifyRenewal(RenewalEvent{Err: err})
+				continue
 			}
-			if ok, err := secret.TokenIsRenewable(); !ok || err != nil {
-				break
+			token, _, _, err := tokenFromSecret(newSecret, nil)
+			if err != nil {
+				s.notifyRenewal(RenewalEvent{Err: err})
+				continue
 			}
-			ttl, err := secret.TokenTTL()
-			if err != nil || ttl == 0 {
-				break
+			s.client.SetToken(token) // SetToken is safe to call from different go routines
+
+			// Vault may have been sealed - and therefore unversioned -
+			// when Authenticate ran its synchronous detection, or the
+			// K/V engine at EnginePath may have been remounted with a
+			// different version since. Re-detect on every successful
+			// (re-)authentication so Get/Create/Delete/List keep using
+			// the right paths once Vault unseals.
+			if err := s.detectEngineVersion(ctx); err != nil {
+				s.notifyRenewal(RenewalEvent{Err: err})
+				continue
 			}
-			timer.Reset(ttl / 2)
+
+			s.notifyRenewal(RenewalEvent{Renewed: true, Secret: newSecret})
+			secret = newSecret
+			continue
 		}
-		ttl = 0
+
+		watcher, err := s.client.NewLifetimeWatcher(&vaultapi.LifetimeWatcherInput{Secret: secret})
+		if err != nil {
+			s.notifyRenewal(RenewalEvent{Err: err})
+			secret = nil
+			continue
+		}
+		go watcher.Start()
+
+		secret = s.watchRenewals(ctx, watcher)
+		if secret == nil {
+			return // ctx was canceled
+		}
+	}
+}
+
+// watchRenewals consumes watcher's RenewCh/DoneCh until either the
+// watcher terminates - in which case it returns an empty, non-nil
+// secret so the caller re-authenticates - or ctx is canceled, in
+// which case it returns nil.
+func (s *Store) watchRenewals(ctx context.Context, watcher *vaultapi.LifetimeWatcher) *vaultapi.Secret {
+	defer watcher.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.DoneCh():
+			s.notifyRenewal(RenewalEvent{Err: err})
+			return &vaultapi.Secret{} // force a re-authentication on the next loop iteration
+		case renewal := <-watcher.RenewCh():
+			s.client.SetToken(renewal.Secret.Auth.ClientToken) // SetToken is safe to call from different go routines
+			s.notifyRenewal(RenewalEvent{Renewed: true, Secret: renewal.Secret})
+		}
+	}
+}
+
+// notifyRenewal sends event on s.RenewalNotify without blocking if
+// no one is receiving.
+func (s *Store) notifyRenewal(event RenewalEvent) {
+	if s.RenewalNotify == nil {
+		return
+	}
+	select {
+	case s.RenewalNotify <- event:
+	default:
 	}
 }
 
@@ -0,0 +1,183 @@
+// Copyright 2019 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+func TestStoreLocation(t *testing.T) {
+	for name, test := range map[string]struct {
+		Store *Store
+		Key   string
+		Want  string
+	}{
+		"v1 default engine path": {
+			Store: &Store{Location: "my-app"},
+			Key:   "my-key",
+			Want:  "kv/my-app/my-key",
+		},
+		"v1 custom engine path": {
+			Store: &Store{EnginePath: "secret", Location: "my-app"},
+			Key:   "my-key",
+			Want:  "secret/my-app/my-key",
+		},
+		"v2 wraps the path under 'data'": {
+			Store: &Store{EnginePath: "secret", Location: "my-app", version: 2},
+			Key:   "my-key",
+			Want:  "secret/data/my-app/my-key",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := test.Store.location(test.Key); got != test.Want {
+				t.Fatalf("got '%s' - want '%s'", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestStoreMetadataLocation(t *testing.T) {
+	s := &Store{EnginePath: "secret", Location: "my-app"}
+	if got, want := s.metadataLocation("my-key"), "secret/metadata/my-app/my-key"; got != want {
+		t.Fatalf("got '%s' - want '%s'", got, want)
+	}
+}
+
+func TestStoreListLocation(t *testing.T) {
+	for name, test := range map[string]struct {
+		Store *Store
+		Want  string
+	}{
+		"v1": {
+			Store: &Store{EnginePath: "secret", Location: "my-app"},
+			Want:  "secret/my-app",
+		},
+		"v2 lists the 'metadata' path": {
+			Store: &Store{EnginePath: "secret", Location: "my-app", version: 2},
+			Want:  "secret/metadata/my-app",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := test.Store.listLocation(""); got != test.Want {
+				t.Fatalf("got '%s' - want '%s'", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	for name, test := range map[string]struct {
+		Keys   []string
+		Limit  int
+		Cursor string
+		Keys2  []string
+		Next   string
+	}{
+		"no limit returns everything": {
+			Keys:  []string{"c", "a", "b"},
+			Limit: 0,
+			Keys2: []string{"a", "b", "c"},
+			Next:  "",
+		},
+		"limit smaller than the result sets next": {
+			Keys:  []string{"c", "a", "b"},
+			Limit: 2,
+			Keys2: []string{"a", "b"},
+			Next:  "b",
+		},
+		"limit larger than the result has no next": {
+			Keys:  []string{"a", "b"},
+			Limit: 5,
+			Keys2: []string{"a", "b"},
+			Next:  "",
+		},
+		"cursor resumes after the previously returned key": {
+			Keys:   []string{"a", "b", "c", "d"},
+			Limit:  2,
+			Cursor: "b",
+			Keys2:  []string{"c", "d"},
+			Next:   "",
+		},
+		"cursor not present in the key set still resumes lexically after it": {
+			Keys:   []string{"a", "c", "d"},
+			Limit:  0,
+			Cursor: "b",
+			Keys2:  []string{"c", "d"},
+			Next:   "",
+		},
+		"cursor past the end returns nothing": {
+			Keys:   []string{"a", "b"},
+			Limit:  0,
+			Cursor: "z",
+			Keys2:  []string{},
+			Next:   "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			keys, next := paginate(test.Keys, test.Limit, test.Cursor)
+			if !reflect.DeepEqual(keys, test.Keys2) {
+				t.Fatalf("got keys %v - want %v", keys, test.Keys2)
+			}
+			if next != test.Next {
+				t.Fatalf("got next '%s' - want '%s'", next, test.Next)
+			}
+		})
+	}
+}
+
+func TestIsCASConflict(t *testing.T) {
+	for name, test := range map[string]struct {
+		Err  error
+		Want bool
+	}{
+		"nil error": {
+			Err:  nil,
+			Want: false,
+		},
+		"unrelated error": {
+			Err:  errors.New("network error"),
+			Want: false,
+		},
+		"400 with an unrelated message is not a CAS conflict": {
+			Err: &vaultapi.ResponseError{
+				StatusCode: http.StatusBadRequest,
+				Errors:     []string{"invalid request body"},
+			},
+			Want: false,
+		},
+		"400 with the check-and-set message": {
+			Err: &vaultapi.ResponseError{
+				StatusCode: http.StatusBadRequest,
+				Errors:     []string{"check-and-set parameter did not match the current version"},
+			},
+			Want: true,
+		},
+		"400 with only the 'did not match the current version' phrasing": {
+			Err: &vaultapi.ResponseError{
+				StatusCode: http.StatusBadRequest,
+				Errors:     []string{"did not match the current version"},
+			},
+			Want: true,
+		},
+		"matching message but wrong status code": {
+			Err: &vaultapi.ResponseError{
+				StatusCode: http.StatusInternalServerError,
+				Errors:     []string{"check-and-set parameter did not match the current version"},
+			},
+			Want: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := isCASConflict(test.Err); got != test.Want {
+				t.Fatalf("got %v - want %v", got, test.Want)
+			}
+		})
+	}
+}